@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"html/template"
@@ -35,9 +36,41 @@ func (i *flagStrs) Set(value string) error {
 	return nil
 }
 
+// cssDownloadSelectors returns the selectors for web.Selectors.CSSDownloads,
+// falling back to the default (inline style attributes and <style> blocks)
+// when -sc was never passed.
+func cssDownloadSelectors(q flagStrs) []web.Selector {
+	if len(q) == 0 {
+		return web.NewSelectors([]string{"*[style],style"})
+	}
+
+	return web.NewSelectors(q)
+}
+
+// loadProxyFile reads one proxy URL per line from path, skipping blank
+// lines and '#' comments.
+func loadProxyFile(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var proxies []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		proxies = append(proxies, line)
+	}
+
+	return proxies, nil
+}
+
 type TplData struct {
-	URL     string
-	Referer string
+	URL      string
+	FinalURL string
+	Referer  string
 
 	Index     int
 	PageIndex int
@@ -53,6 +86,9 @@ func main() {
 	var downloadsQ flagStrs
 	var printsQ flagStrs
 	var titleQ flagStrs
+	var cssQ flagStrs
+	var hostsQ flagStrs
+	var outer bool
 	var dir string
 	var downloadFormat string
 	var printFormat string
@@ -61,6 +97,15 @@ func main() {
 	var dry bool
 	var noprogress bool
 	var concurrency int
+	var redirects string
+	var rateLimit float64
+	var retryMax int
+	var retryBase time.Duration
+	var retryMaxDelay time.Duration
+	var failLog string
+	var robotsMode string
+	var proxiesQ flagStrs
+	var proxyFile string
 	flag.BoolVar(&dry, "n", false, "dry run and print what would be downloaded (http requests will still be made for pages)")
 	flag.BoolVar(&noprogress, "np", false, "no progress")
 
@@ -68,11 +113,46 @@ func main() {
 	flag.Var(&downloadsQ, "sd", "selector for downloads (can be specified multiple times)")
 	flag.Var(&printsQ, "sp", "selector for printing to stdout (can be specified multiple times)")
 	flag.Var(&titleQ, "st", "selector for title (can be specified multiple times)")
+	flag.Var(&cssQ, "sc", "selector for css url(...) downloads, e.g. fonts/images referenced from style attributes or <style> blocks (can be specified multiple times); defaults to '*[style],style', pass -sc '' to disable")
+
+	flag.Var(&hostsQ, "host", "allowed host glob pattern, e.g. '*.mysite.tld,cdn.mysite.tld' (can be specified multiple times, comma separated); by default crawling stays on each seed URL's own host, -host adds further hosts that are also allowed")
+	flag.BoolVar(&outer, "outer", false, "do not restrict crawling to the seed URL's host; without -host, follow links to any host, with -host, follow only links to hosts matching -host")
 
 	flag.BoolVar(&numRevPages, "rl", false, "{{ .PageIndex }} in -f will be the inverse")
 	flag.BoolVar(&numRevDownloads, "rd", false, "{{ .Index }} in -f will be the inverse")
 
 	flag.IntVar(&concurrency, "c", 8, "download concurrency")
+	flag.StringVar(
+		&redirects,
+		"redirect",
+		string(web.RedirectFollow),
+		`how to handle 3xx responses: follow, no-follow or record
+  - follow:    follow redirects transparently (default)
+  - no-follow: treat a redirect as a broken link and instead queue its
+               Location as a newly discovered page/download
+  - record:    follow redirects but expose .FinalURL (see -df/-pf) for
+               pages that were redirected`,
+	)
+
+	flag.Float64Var(&rateLimit, "rate", 0, "max requests/sec to any single host, 0 means unlimited")
+	flag.IntVar(&retryMax, "retry", 1, "max attempts per request on transient failures (network errors, 429, 5xx), 1 disables retrying")
+	flag.DurationVar(&retryBase, "retry-base", time.Second, "initial retry backoff, doubled on every further attempt")
+	flag.DurationVar(&retryMaxDelay, "retry-max-delay", time.Minute, "cap on the retry backoff delay")
+	flag.StringVar(&failLog, "fail-log", "", "write every URL Recurse gave up on as JSONL to this file, so they can be retried later")
+	flag.StringVar(
+		&robotsMode,
+		"robots",
+		string(web.RobotsOff),
+		`how to treat robots.txt: off, warn or enforce
+  - off:     ignore robots.txt entirely (default)
+  - warn:    fetch robots.txt and report disallowed URLs, but crawl them anyway
+  - enforce: fetch robots.txt and drop disallowed URLs before crawling them
+any mode other than off also honours a host's Crawl-delay as a minimum
+interval between requests to that host, whether or not -rate is set`,
+	)
+
+	flag.Var(&proxiesQ, "proxy", "proxy URL (http, https or socks5) to route requests through, round-robin across all given (can be specified multiple times)")
+	flag.StringVar(&proxyFile, "proxy-file", "", "file with one proxy URL per line, appended to -proxy")
 
 	flag.StringVar(&dir, "d", ".", "Destination directory")
 	defFormat := filepath.Join(
@@ -89,6 +169,8 @@ func main() {
 
 available fields: ('page' here refers to the page the current download was found on)
   - .URL         url of the download.
+  - .FinalURL    url of the download after following redirects (same as .URL
+                 unless -redirect=record).
   - .Referer     url of the page.
   - .Index:      index of the download within the page.
   - .PageIndex:  index of the page within the list of found pages.
@@ -117,6 +199,8 @@ available functions:
 available fields:
   - .Data:       a 2-dimensional array of items matched with -sp
   - .URL         url of the current page.
+  - .FinalURL    url of the current page after following redirects (same as
+                 .URL unless -redirect=record).
   - .Referer     url of the referer.
   - .PageIndex:  index of the page within the list of found pages.
   - .Title:      title of the page.
@@ -138,12 +222,51 @@ available functions: see -df
 	}
 	flag.Parse()
 
+	switch web.RedirectMode(redirects) {
+	case web.RedirectFollow, web.RedirectNoFollow, web.RedirectRecord:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -redirect value: %s\n", redirects)
+		os.Exit(1)
+	}
+
+	switch web.RobotsMode(robotsMode) {
+	case web.RobotsOff, web.RobotsWarn, web.RobotsEnforce:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -robots value: %s\n", robotsMode)
+		os.Exit(1)
+	}
+
+	proxies := []string(proxiesQ)
+	if proxyFile != "" {
+		fileProxies, err := loadProxyFile(proxyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read -proxy-file: %s\n", err.Error())
+			os.Exit(1)
+		}
+		proxies = append(proxies, fileProxies...)
+	}
+
 	var cancelErr error
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
 	conf := web.Config{
-		UserAgent:   "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36",
-		Concurrency: concurrency,
+		UserAgent:       "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36",
+		Concurrency:     concurrency,
+		FollowRedirects: web.RedirectMode(redirects),
+		RateLimit:       rateLimit,
+		Robots:          web.RobotsMode(robotsMode),
+		Proxies:         proxies,
+		Retry: web.Retry{
+			Max:      retryMax,
+			Base:     retryBase,
+			MaxDelay: retryMaxDelay,
+		},
+		OnRetry: func(p web.PageInfo, err *web.RetryError) {
+			if noprogress {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "\nretrying %s (%d/%d): %s\n", p.URL, err.Attempt, err.Max, err.Err)
+		},
 	}
 
 	w := web.New(conf)
@@ -162,8 +285,13 @@ available functions: see -df
 			reftitle = p.Ref.Title
 			refindex = p.Ref.Index
 		}
+		finalURL := p.URL.String()
+		if p.FinalURL != nil {
+			finalURL = p.FinalURL.String()
+		}
 		return TplData{
 			URL:       p.URL.String(),
+			FinalURL:  finalURL,
 			Referer:   refurl,
 			Index:     p.Index,
 			PageIndex: refindex,
@@ -234,11 +362,20 @@ available functions: see -df
 		ProgressInterval: time.Millisecond * 50,
 		ReverseLinks:     numRevPages,
 		ReverseDownloads: numRevDownloads,
+		AllowedHosts:     web.ParseHostLists(hostsQ),
+		SameHostOnly:     !outer,
+		OnHostSkipped: func(p web.PageInfo) {
+			if noprogress {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "\nskipped (host not allowed): %s\n", p.URL)
+		},
 		Selectors: web.Selectors{
-			Links:     web.NewSelectors(linksQ),
-			Downloads: web.NewSelectors(downloadsQ),
-			Prints:    web.NewSelectors(printsQ),
-			Titles:    web.NewSelectors(titleQ),
+			Links:        web.NewSelectors(linksQ),
+			Downloads:    web.NewSelectors(downloadsQ),
+			Prints:       web.NewSelectors(printsQ),
+			Titles:       web.NewSelectors(titleQ),
+			CSSDownloads: cssDownloadSelectors(cssQ),
 		},
 		Progress: func(err error, i, n uint64) {
 			if err != nil {
@@ -338,7 +475,14 @@ available functions: see -df
 		os.Exit(1)
 	}()
 
-	err = w.Recurse(ctx, rc)
+	failures, err := w.Recurse(ctx, rc)
+
+	if failLog != "" {
+		if ferr := writeFailLog(failLog, failures); ferr != nil {
+			fmt.Fprintf(os.Stderr, "failed to write -fail-log: %s\n", ferr.Error())
+		}
+	}
+
 	if err != nil {
 		if cancelErr != nil {
 			err = cancelErr
@@ -347,3 +491,33 @@ available functions: see -df
 		os.Exit(1)
 	}
 }
+
+type failLogEntry struct {
+	URL     string `json:"url"`
+	Referer string `json:"referer"`
+	Status  int    `json:"status"`
+	Error   string `json:"error"`
+}
+
+func writeFailLog(path string, failures []web.Failure) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, fail := range failures {
+		err := enc.Encode(failLogEntry{
+			URL:     fail.URL,
+			Referer: fail.Referer,
+			Status:  fail.Status,
+			Error:   fail.Err.Error(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package robots
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// DefaultSize is the cache size used when Cache is constructed with
+// size <= 0.
+const DefaultSize = 128
+
+type entry struct {
+	host  string
+	rules *Rules
+}
+
+// Cache fetches and caches a Rules per host, bounded to size entries
+// (least recently used evicted first). Fetch failures (network errors,
+// non-200 responses, missing robots.txt) cache an empty, permissive
+// Rules rather than erroring, since the absence of a robots.txt means
+// everything is allowed.
+type Cache struct {
+	client    *http.Client
+	userAgent string
+	size      int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// NewCache returns a Cache that fetches robots.txt using client and
+// userAgent, bounded to size hosts (size <= 0 uses DefaultSize).
+func NewCache(client *http.Client, userAgent string, size int) *Cache {
+	if client == nil {
+		client = &http.Client{}
+	}
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	return &Cache{
+		client:    client,
+		userAgent: userAgent,
+		size:      size,
+		order:     list.New(),
+		entries:   make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached (or freshly fetched) Rules for u's host.
+func (c *Cache) Get(ctx context.Context, u *url.URL) (*Rules, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[u.Host]; ok {
+		c.order.MoveToFront(el)
+		rules := el.Value.(*entry).rules
+		c.mu.Unlock()
+		return rules, nil
+	}
+	c.mu.Unlock()
+
+	rules, err := c.fetch(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	el := c.order.PushFront(&entry{host: u.Host, rules: rules})
+	c.entries[u.Host] = el
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).host)
+	}
+	c.mu.Unlock()
+
+	return rules, nil
+}
+
+func (c *Cache) fetch(ctx context.Context, u *url.URL) (*Rules, error) {
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return &Rules{}, nil
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return &Rules{}, nil
+	}
+
+	return Parse(res.Body, ProductToken), nil
+}
@@ -0,0 +1,143 @@
+// Package robots implements a minimal robots.txt parser and a small
+// per-host cache for fetching it over HTTP.
+package robots
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProductToken identifies this crawler in robots.txt "User-agent:"
+// groups. It is deliberately separate from the (often browser-spoofed)
+// User-Agent HTTP header used to fetch pages and robots.txt itself,
+// since matching group rules against that string would spuriously match
+// any group named after a token the spoofed string happens to contain
+// (e.g. "mozilla" or "chrome").
+const ProductToken = "webdl"
+
+// Rules is the parsed ruleset that applies to one user-agent.
+type Rules struct {
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// Allowed reports whether path is allowed by these rules, using the
+// standard longest-matching-prefix resolution (an Allow rule only wins
+// a tie against a Disallow rule of the same length).
+func (r *Rules) Allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+
+	allow, allowLen := false, -1
+	for _, p := range r.allow {
+		if strings.HasPrefix(path, p) && len(p) > allowLen {
+			allow, allowLen = true, len(p)
+		}
+	}
+	disallow, disallowLen := false, -1
+	for _, p := range r.disallow {
+		if strings.HasPrefix(path, p) && len(p) > disallowLen {
+			disallow, disallowLen = true, len(p)
+		}
+	}
+
+	if !disallow {
+		return true
+	}
+
+	return allow && allowLen >= disallowLen
+}
+
+// CrawlDelay returns the Crawl-delay directive for this ruleset, or 0
+// if none was set.
+func (r *Rules) CrawlDelay() time.Duration {
+	if r == nil {
+		return 0
+	}
+
+	return r.crawlDelay
+}
+
+// Parse parses a robots.txt document, keeping only the rules from the
+// group that applies to productToken (see ProductToken), falling back
+// to the "*" group.
+func Parse(r io.Reader, productToken string) *Rules {
+	type group struct {
+		agents   []string
+		allow    []string
+		disallow []string
+		delay    time.Duration
+	}
+
+	var groups []*group
+	var cur *group
+	freshGroup := true
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		val := strings.TrimSpace(kv[1])
+
+		switch key {
+		case "user-agent":
+			if cur == nil || !freshGroup {
+				cur = &group{}
+				groups = append(groups, cur)
+				freshGroup = true
+			}
+			cur.agents = append(cur.agents, strings.ToLower(val))
+		case "disallow":
+			freshGroup = false
+			if cur != nil && val != "" {
+				cur.disallow = append(cur.disallow, val)
+			}
+		case "allow":
+			freshGroup = false
+			if cur != nil && val != "" {
+				cur.allow = append(cur.allow, val)
+			}
+		case "crawl-delay":
+			freshGroup = false
+			if cur != nil {
+				if secs, err := strconv.ParseFloat(val, 64); err == nil {
+					cur.delay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	token := strings.ToLower(productToken)
+	rules := &Rules{}
+	matchedSpecific := false
+	for _, g := range groups {
+		for _, a := range g.agents {
+			switch {
+			case a == "*" && !matchedSpecific:
+				rules = &Rules{allow: g.allow, disallow: g.disallow, crawlDelay: g.delay}
+			case a != "*" && token != "" && strings.Contains(token, a):
+				rules = &Rules{allow: g.allow, disallow: g.disallow, crawlDelay: g.delay}
+				matchedSpecific = true
+			}
+		}
+	}
+
+	return rules
+}
@@ -0,0 +1,95 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRulesAllowed(t *testing.T) {
+	doc := `
+User-agent: *
+Disallow: /private/
+Allow: /private/public/
+Disallow: /tmp
+`
+	rules := Parse(strings.NewReader(doc), ProductToken)
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/private/", false},
+		{"/private/page", false},
+		{"/private/public/", true},
+		{"/private/public/page", true},
+		{"/tmpfile", false},
+		{"/other", true},
+	}
+
+	for _, c := range cases {
+		if got := rules.Allowed(c.path); got != c.want {
+			t.Errorf("Allowed(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestRulesAllowedNilIsPermissive(t *testing.T) {
+	var rules *Rules
+	if !rules.Allowed("/anything") {
+		t.Error("expected a nil Rules to allow everything")
+	}
+	if rules.CrawlDelay() != 0 {
+		t.Error("expected a nil Rules to have no crawl delay")
+	}
+}
+
+func TestParseSpecificGroupWinsOverWildcard(t *testing.T) {
+	doc := `
+User-agent: *
+Disallow: /
+
+User-agent: webdl
+Disallow:
+Allow: /
+`
+	rules := Parse(strings.NewReader(doc), ProductToken)
+	if !rules.Allowed("/anything") {
+		t.Error("expected the webdl-specific group to override the wildcard Disallow: /")
+	}
+}
+
+func TestParseDoesNotMatchSubstringOfUnrelatedAgent(t *testing.T) {
+	// "bot" is a substring of "webdl-bot", but our product token
+	// ("webdl") is not a substring of "bot" - the group must not apply.
+	doc := `
+User-agent: bot
+Disallow: /
+
+User-agent: *
+Allow: /
+`
+	rules := Parse(strings.NewReader(doc), ProductToken)
+	if !rules.Allowed("/anything") {
+		t.Error("expected the 'bot' group not to match product token 'webdl', falling back to '*'")
+	}
+}
+
+func TestParseCrawlDelay(t *testing.T) {
+	doc := `
+User-agent: *
+Crawl-delay: 2.5
+`
+	rules := Parse(strings.NewReader(doc), ProductToken)
+	if got, want := rules.CrawlDelay(), 2500*time.Millisecond; got != want {
+		t.Errorf("CrawlDelay() = %v, want %v", got, want)
+	}
+}
+
+func TestParseEmptyDocumentIsPermissive(t *testing.T) {
+	rules := Parse(strings.NewReader(""), ProductToken)
+	if !rules.Allowed("/anything") {
+		t.Error("expected an empty robots.txt to allow everything")
+	}
+}
@@ -0,0 +1,43 @@
+package web
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Go's regexp (RE2) has no backreferences, so the single- and
+// double-quoted and bare forms are matched as separate alternatives
+// instead of a single group with a \1 closing quote.
+var cssURLRE = regexp.MustCompile(`url\(\s*(?:'([^']+)'|"([^"]+)"|([^'")]+))\s*\)`)
+
+// ExtractCSSURLs returns every url(...) reference found in css, each
+// resolved against base.
+func ExtractCSSURLs(base *url.URL, css string) []*url.URL {
+	matches := cssURLRE.FindAllStringSubmatch(css, -1)
+	urls := make([]*url.URL, 0, len(matches))
+	for _, m := range matches {
+		ref := m[1]
+		if ref == "" {
+			ref = m[2]
+		}
+		if ref == "" {
+			ref = m[3]
+		}
+		ref = strings.TrimSpace(ref)
+
+		href, err := HREF(base, ref)
+		if err != nil {
+			continue
+		}
+		urls = append(urls, href)
+	}
+
+	return urls
+}
+
+// isCSSAsset reports whether u refers to a stylesheet, based on its
+// path extension.
+func isCSSAsset(u *url.URL) bool {
+	return strings.HasSuffix(strings.ToLower(u.Path), ".css")
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"net/url"
+	"testing"
+)
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatalf("url.Parse(%q): %s", s, err)
+	}
+	return u
+}
+
+func TestExtractCSSURLs(t *testing.T) {
+	base := mustParseURL(t, "https://example.com/css/site.css")
+
+	css := `
+		.a { background: url(bg.png); }
+		.b { background: url('img/b.png'); }
+		.c { background: url("img/c.png"); }
+		.d { background: url(  /abs.png  ); }
+		.e { background: url(https://cdn.example.com/e.png); }
+	`
+
+	urls := ExtractCSSURLs(base, css)
+	want := []string{
+		"https://example.com/css/site.css/bg.png",
+		"https://example.com/css/site.css/img/b.png",
+		"https://example.com/css/site.css/img/c.png",
+		"https://example.com/abs.png",
+		"https://cdn.example.com/e.png",
+	}
+
+	if len(urls) != len(want) {
+		t.Fatalf("got %d urls, want %d: %v", len(urls), len(want), urls)
+	}
+	for i, u := range urls {
+		if u.String() != want[i] {
+			t.Errorf("urls[%d] = %s, want %s", i, u.String(), want[i])
+		}
+	}
+}
+
+func TestExtractCSSURLsNoMatches(t *testing.T) {
+	base := mustParseURL(t, "https://example.com/css/site.css")
+	if urls := ExtractCSSURLs(base, ".a { color: red; }"); len(urls) != 0 {
+		t.Errorf("expected no urls, got %v", urls)
+	}
+}
+
+func TestIsCSSAsset(t *testing.T) {
+	cases := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/site.css", true},
+		{"https://example.com/site.CSS", true},
+		{"https://example.com/site.css?v=2", true},
+		{"https://example.com/site.png", false},
+		{"https://example.com/css/", false},
+	}
+
+	for _, c := range cases {
+		u := mustParseURL(t, c.url)
+		if got := isCSSAsset(u); got != c.want {
+			t.Errorf("isCSSAsset(%q) = %v, want %v", c.url, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchHost reports whether host matches the given glob-style pattern.
+// '*' matches any sequence of characters, so "*.example.com" matches
+// "cdn.example.com" but not the apex "example.com" itself.
+func MatchHost(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	host = strings.ToLower(host)
+	ok, err := path.Match(pattern, host)
+	return err == nil && ok
+}
+
+// MatchHosts reports whether host matches any of the given patterns.
+func MatchHosts(patterns []string, host string) bool {
+	for _, p := range patterns {
+		if MatchHost(p, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseHostList splits a comma separated list of host glob patterns,
+// trimming whitespace around each entry and dropping empty ones.
+func ParseHostList(s string) []string {
+	sp := strings.Split(s, ",")
+	l := make([]string, 0, len(sp))
+	for _, h := range sp {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		l = append(l, h)
+	}
+
+	return l
+}
+
+// ParseHostLists is ParseHostList over multiple comma separated lists,
+// e.g. values collected from a repeatable CLI flag.
+func ParseHostLists(s []string) []string {
+	l := make([]string, 0, len(s))
+	for i := range s {
+		l = append(l, ParseHostList(s[i])...)
+	}
+
+	return l
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchHost(t *testing.T) {
+	cases := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "EXAMPLE.COM", true},
+		{"*.example.com", "cdn.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "a.b.example.com", true},
+		{"cdn.example.com", "cdn.example.org", false},
+		{"*", "anything.tld", true},
+		{" example.com ", "example.com", true},
+		{"[", "example.com", false},
+	}
+
+	for _, c := range cases {
+		if got := MatchHost(c.pattern, c.host); got != c.want {
+			t.Errorf("MatchHost(%q, %q) = %v, want %v", c.pattern, c.host, got, c.want)
+		}
+	}
+}
+
+func TestMatchHosts(t *testing.T) {
+	patterns := []string{"*.example.com", "other.tld"}
+
+	if !MatchHosts(patterns, "cdn.example.com") {
+		t.Error("expected cdn.example.com to match *.example.com")
+	}
+	if !MatchHosts(patterns, "other.tld") {
+		t.Error("expected other.tld to match other.tld")
+	}
+	if MatchHosts(patterns, "unrelated.tld") {
+		t.Error("expected unrelated.tld not to match")
+	}
+	if MatchHosts(nil, "example.com") {
+		t.Error("expected no patterns to match nothing")
+	}
+}
+
+func TestParseHostList(t *testing.T) {
+	got := ParseHostList(" a.com, b.com ,, c.com")
+	want := []string{"a.com", "b.com", "c.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHostList() = %v, want %v", got, want)
+	}
+
+	if got := ParseHostList(""); len(got) != 0 {
+		t.Errorf("ParseHostList(\"\") = %v, want empty", got)
+	}
+}
+
+func TestParseHostLists(t *testing.T) {
+	got := ParseHostLists([]string{"a.com, b.com", "c.com"})
+	want := []string{"a.com", "b.com", "c.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHostLists() = %v, want %v", got, want)
+	}
+}
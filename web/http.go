@@ -1,38 +1,118 @@
 package web
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
 var multispaceRE = regexp.MustCompile(`\s+`)
 
-func (w *Web) get(ctx context.Context, p PageInfo) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", p.URL.String(), nil)
-	if err != nil {
-		return nil, err
+func (w *Web) get(ctx context.Context, p PageInfo) (io.ReadCloser, []string, error) {
+	attempts := w.c.Retry.Max
+	if attempts <= 0 {
+		attempts = 1
 	}
-	if p.Ref != nil {
-		req.Header.Set("referer", p.Ref.URL.String())
+	delay := w.c.Retry.Base
+	if delay <= 0 {
+		delay = time.Second
 	}
-	res, err := w.c.Client.Do(req)
-	if err != nil {
-		return nil, err
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err := w.limiter.Wait(ctx, p.URL.Host); err != nil {
+			return nil, nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", p.URL.String(), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if p.Ref != nil {
+			req.Header.Set("referer", p.Ref.URL.String())
+		}
+
+		var chain *[]string
+		if w.c.FollowRedirects == RedirectRecord {
+			chain = &[]string{}
+			req = req.WithContext(withRedirectChain(req.Context(), chain))
+		}
+
+		res, err := w.c.Client.Do(req)
+		retry, wait := false, time.Duration(0)
+		switch {
+		case err != nil:
+			retry = retryableError(err)
+		default:
+			retry, wait = retryableStatus(res)
+		}
+
+		if !retry || attempt == attempts {
+			if err != nil {
+				return nil, nil, err
+			}
+			if res.StatusCode < 200 || res.StatusCode >= 300 {
+				body, _ := io.ReadAll(res.Body)
+				res.Body.Close()
+				return nil, nil, &HTTPError{URL: p.URL.String(), Status: res.StatusCode, Body: body}
+			}
+			if chain != nil {
+				return res.Body, *chain, nil
+			}
+			return res.Body, nil, nil
+		}
+
+		lastErr = err
+		if lastErr == nil {
+			lastErr = fmt.Errorf("http %d", res.StatusCode)
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+
+		if wait <= 0 {
+			wait = delay
+			delay *= 2
+			if w.c.Retry.MaxDelay > 0 && delay > w.c.Retry.MaxDelay {
+				delay = w.c.Retry.MaxDelay
+			}
+		}
+
+		if w.c.OnRetry != nil {
+			w.c.OnRetry(p, &RetryError{Err: lastErr, Attempt: attempt, Max: attempts})
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, nil, ctx.Err()
+		}
 	}
-	return res.Body, nil
+
+	return nil, nil, lastErr
 }
 
 func (w *Web) page(ctx context.Context, pi PageInfo, s Selectors) (*Page, error) {
-	r, err := w.get(ctx, pi)
+	r, chain, err := w.get(ctx, pi)
 	if err != nil {
 		return nil, err
 	}
+	if chain != nil {
+		pi.RedirectChain = chain
+		if final, err := url.Parse(chain[len(chain)-1]); err == nil {
+			pi.FinalURL = final
+		}
+	}
 	doc, err := goquery.NewDocumentFromReader(r)
 	r.Close()
 	if err != nil {
@@ -67,6 +147,9 @@ func (w *Web) page(ctx context.Context, pi PageInfo, s Selectors) (*Page, error)
 	p := newPage(pi)
 	qry(s.Links, addLink(&p.Links))
 	qry(s.Downloads, addLink(&p.Downloads))
+	qry(s.CSSDownloads, func(qix int, data string) {
+		p.Downloads = append(p.Downloads, ExtractCSSURLs(pi.URL, data)...)
+	})
 	qry(s.Titles, func(qix int, data string) {
 		if p.Title == "" {
 			p.Title = strings.TrimSpace(multispaceRE.ReplaceAllString(data, " "))
@@ -85,12 +168,40 @@ func (w *Web) page(ctx context.Context, pi PageInfo, s Selectors) (*Page, error)
 	return p, nil
 }
 
-func (w *Web) download(ctx context.Context, p PageInfo, cb DownloadCallback) error {
-	r, err := w.get(ctx, p)
+// download fetches p and hands its body to cb. When p (or its FinalURL,
+// which is only known when redirects are recorded) is a CSS asset, its contents are also
+// scanned for url(...) references, returned so Recurse can enqueue them
+// as further downloads resolved against the stylesheet's own URL rather
+// than the page that linked to it.
+func (w *Web) download(ctx context.Context, p PageInfo, cb DownloadCallback) ([]*url.URL, error) {
+	r, chain, err := w.get(ctx, p)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer r.Close()
 
-	return cb(p, r)
+	if chain != nil {
+		p.RedirectChain = chain
+		if final, err := url.Parse(chain[len(chain)-1]); err == nil {
+			p.FinalURL = final
+		}
+	}
+
+	cssBase := p.URL
+	if p.FinalURL != nil {
+		cssBase = p.FinalURL
+	}
+	if !isCSSAsset(cssBase) {
+		return nil, cb(p, r)
+	}
+
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := cb(p, bytes.NewReader(buf)); err != nil {
+		return nil, err
+	}
+
+	return ExtractCSSURLs(cssBase, string(buf)), nil
 }
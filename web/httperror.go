@@ -0,0 +1,25 @@
+package web
+
+import "fmt"
+
+// HTTPError is returned when a request resolves to a non-2xx response
+// (after any retries are exhausted), instead of silently handing the
+// error page's body to the caller as if it were the requested resource.
+type HTTPError struct {
+	URL    string
+	Status int
+	Body   []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d", e.URL, e.Status)
+}
+
+// Failure records one URL that Recurse gave up on, for callers that
+// want to retry only the broken URLs later.
+type Failure struct {
+	URL     string
+	Referer string
+	Status  int
+	Err     error
+}
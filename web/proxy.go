@@ -0,0 +1,61 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+)
+
+// ProxySelector picks the proxy URL to dial req through, or nil to dial
+// directly. When set on Config, it takes precedence over Config.Proxies.
+type ProxySelector func(*http.Request) *url.URL
+
+// proxyRotator round-robins through a fixed list of proxy URLs (http,
+// https or socks5), advancing on every call regardless of outcome. Since
+// Web.get issues a fresh *http.Request per retry attempt, a failed
+// request naturally rotates to the next proxy on retry instead of
+// hammering the same broken exit.
+type proxyRotator struct {
+	proxies []*url.URL
+	next    uint64
+}
+
+func newProxyRotator(raw []string) *proxyRotator {
+	proxies := make([]*url.URL, 0, len(raw))
+	for _, s := range raw {
+		u, err := url.Parse(s)
+		if err != nil {
+			continue
+		}
+		proxies = append(proxies, u)
+	}
+	if len(proxies) == 0 {
+		return nil
+	}
+
+	return &proxyRotator{proxies: proxies}
+}
+
+func (r *proxyRotator) proxy(*http.Request) (*url.URL, error) {
+	i := atomic.AddUint64(&r.next, 1) - 1
+	return r.proxies[i%uint64(len(r.proxies))], nil
+}
+
+// proxyFunc builds an http.Transport.Proxy function from selector (when
+// set) or a round-robin over proxies. Returns nil when neither is set,
+// leaving the transport's existing behaviour (e.g. ProxyFromEnvironment)
+// untouched.
+func proxyFunc(proxies []string, selector ProxySelector) func(*http.Request) (*url.URL, error) {
+	if selector != nil {
+		return func(req *http.Request) (*url.URL, error) {
+			return selector(req), nil
+		}
+	}
+
+	r := newProxyRotator(proxies)
+	if r == nil {
+		return nil
+	}
+
+	return r.proxy
+}
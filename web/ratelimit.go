@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hostRateLimiter enforces a minimum interval between requests to the
+// same host. A zero interval (the default, RateLimit <= 0) disables
+// limiting. Per-host overrides (e.g. from a robots.txt Crawl-delay) can
+// be set with SetMinInterval and always take precedence over the
+// default when larger.
+type hostRateLimiter struct {
+	mu        sync.Mutex
+	next      map[string]time.Time
+	interval  time.Duration
+	overrides map[string]time.Duration
+}
+
+func newHostRateLimiter(rps float64) *hostRateLimiter {
+	var interval time.Duration
+	if rps > 0 {
+		interval = time.Duration(float64(time.Second) / rps)
+	}
+
+	return &hostRateLimiter{
+		next:      make(map[string]time.Time),
+		interval:  interval,
+		overrides: make(map[string]time.Duration),
+	}
+}
+
+// SetMinInterval overrides the minimum delay between requests for host.
+func (l *hostRateLimiter) SetMinInterval(host string, d time.Duration) {
+	l.mu.Lock()
+	l.overrides[host] = d
+	l.mu.Unlock()
+}
+
+// Wait blocks until a request to host is allowed to fire, or ctx is done.
+func (l *hostRateLimiter) Wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	interval := l.interval
+	if o, ok := l.overrides[host]; ok && o > interval {
+		interval = o
+	}
+	if interval <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	next, ok := l.next[host]
+	if !ok || now.After(next) {
+		next = now
+	}
+	l.next[host] = next.Add(interval)
+	l.mu.Unlock()
+
+	wait := next.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
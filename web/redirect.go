@@ -0,0 +1,101 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// RedirectMode controls how Web handles 3xx responses.
+type RedirectMode string
+
+const (
+	// RedirectFollow follows redirects transparently, the default
+	// behaviour of an unconfigured http.Client.
+	RedirectFollow RedirectMode = "follow"
+	// RedirectNoFollow surfaces a redirect as a *RedirectError instead
+	// of following it.
+	RedirectNoFollow RedirectMode = "no-follow"
+	// RedirectRecord follows redirects like RedirectFollow but records
+	// every URL visited on the resulting PageInfo.RedirectChain.
+	RedirectRecord RedirectMode = "record"
+)
+
+const maxRedirects = 10
+
+// RedirectError is surfaced through Progress when FollowRedirects is
+// RedirectNoFollow and a request resolves to a 3xx response.
+type RedirectError struct {
+	StatusCode int
+	Location   string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("redirect %d to %s", e.StatusCode, e.Location)
+}
+
+type redirectChainKey struct{}
+
+func withRedirectChain(ctx context.Context, chain *[]string) context.Context {
+	return context.WithValue(ctx, redirectChainKey{}, chain)
+}
+
+func redirectChainFrom(ctx context.Context) *[]string {
+	chain, _ := ctx.Value(redirectChainKey{}).(*[]string)
+	return chain
+}
+
+// redirectRoundTripper implements RedirectMode on top of a plain
+// http.RoundTripper. The Client installed on Web always disables its own
+// redirect following (see New) so that every hop passes through here.
+type redirectRoundTripper struct {
+	next http.RoundTripper
+	mode RedirectMode
+}
+
+func (rt *redirectRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	chain := redirectChainFrom(req.Context())
+	cur := req
+	for hop := 0; ; hop++ {
+		if chain != nil {
+			*chain = append(*chain, cur.URL.String())
+		}
+
+		res, err := rt.next.RoundTrip(cur)
+		if err != nil {
+			return nil, err
+		}
+
+		loc := res.Header.Get("Location")
+		if loc == "" || res.StatusCode < 300 || res.StatusCode >= 400 {
+			return res, nil
+		}
+
+		if rt.mode == RedirectNoFollow {
+			res.Body.Close()
+			return nil, &RedirectError{StatusCode: res.StatusCode, Location: loc}
+		}
+
+		if hop >= maxRedirects {
+			res.Body.Close()
+			return nil, fmt.Errorf("webdl: stopped after %d redirects", maxRedirects)
+		}
+
+		next, err := cur.URL.Parse(loc)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		nreq := cur.Clone(cur.Context())
+		nreq.URL = next
+		nreq.Host = ""
+		if res.StatusCode == http.StatusSeeOther && cur.Method != http.MethodGet && cur.Method != http.MethodHead {
+			nreq.Method = http.MethodGet
+			nreq.Body = nil
+			nreq.ContentLength = 0
+			nreq.Header.Del("Content-Type")
+		}
+		cur = nreq
+	}
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Retry configures transient-failure retries shared by page and download
+// fetches. Max is the total number of attempts (0 or 1 means no retry);
+// the delay starts at Base and doubles on every further attempt, capped
+// at MaxDelay.
+type Retry struct {
+	Max      int
+	Base     time.Duration
+	MaxDelay time.Duration
+}
+
+// RetryError is passed to Config.OnRetry for every attempt that failed
+// but will be retried; it never reaches Progress as a final failure.
+type RetryError struct {
+	Err     error
+	Attempt int
+	Max     int
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("attempt %d/%d failed, retrying: %s", e.Attempt, e.Max, e.Err)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// retryableStatus reports whether res (status-wise) warrants a retry,
+// and how long to wait before the next attempt if the server told us
+// via Retry-After (0 means "use the computed backoff instead").
+func retryableStatus(res *http.Response) (bool, time.Duration) {
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+		return true, retryAfter(res)
+	case res.StatusCode >= 500:
+		return true, 0
+	default:
+		return false, 0
+	}
+}
+
+func retryAfter(res *http.Response) time.Duration {
+	ra := res.Header.Get("Retry-After")
+	if ra == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(ra); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(ra); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// retryableError reports whether err (as returned by http.Client.Do)
+// warrants a retry. http.Client.Do wraps every transport error in a
+// *url.Error, and *url.Error itself satisfies net.Error (it forwards
+// Timeout/Temporary to the wrapped error), so checking net.Error against
+// err directly would match non-network failures too, such as a
+// RedirectError from RedirectNoFollow, an unsupported proxy scheme, or a
+// context cancellation. Unwrap the *url.Error first and exclude those
+// explicitly.
+func retryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rerr *RedirectError
+	if errors.As(err, &rerr) {
+		return false
+	}
+
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		err = uerr.Err
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,95 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryableStatus(t *testing.T) {
+	cases := []struct {
+		status   int
+		wantRoot bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNotFound, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+	}
+
+	for _, c := range cases {
+		res := &http.Response{StatusCode: c.status, Header: http.Header{}}
+		retry, _ := retryableStatus(res)
+		if retry != c.wantRoot {
+			t.Errorf("retryableStatus(%d) = %v, want %v", c.status, retry, c.wantRoot)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	res := &http.Response{Header: http.Header{}}
+	if d := retryAfter(res); d != 0 {
+		t.Errorf("no Retry-After: got %v, want 0", d)
+	}
+
+	res.Header.Set("Retry-After", "5")
+	if d := retryAfter(res); d != 5*time.Second {
+		t.Errorf("Retry-After: 5 => got %v, want 5s", d)
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	res.Header.Set("Retry-After", future)
+	if d := retryAfter(res); d <= 0 || d > time.Hour {
+		t.Errorf("Retry-After: %s => got %v, want ~1h", future, d)
+	}
+
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	res.Header.Set("Retry-After", past)
+	if d := retryAfter(res); d != 0 {
+		t.Errorf("Retry-After in the past: got %v, want 0", d)
+	}
+
+	res.Header.Set("Retry-After", "not-a-date")
+	if d := retryAfter(res); d != 0 {
+		t.Errorf("Retry-After garbage: got %v, want 0", d)
+	}
+}
+
+type fakeNetErr struct{}
+
+func (fakeNetErr) Error() string   { return "fake net error" }
+func (fakeNetErr) Timeout() bool   { return true }
+func (fakeNetErr) Temporary() bool { return true }
+
+func TestRetryableError(t *testing.T) {
+	if !retryableError(fakeNetErr{}) {
+		t.Error("expected a net.Error to be retryable")
+	}
+	if !retryableError(fmt.Errorf("wrapped: %w", fakeNetErr{})) {
+		t.Error("expected a wrapped net.Error to be retryable")
+	}
+	if retryableError(errors.New("boom")) {
+		t.Error("expected a plain error not to be retryable")
+	}
+
+	// http.Client.Do wraps every transport error in a *url.Error, which
+	// itself satisfies net.Error - these must not be mistaken for an
+	// actual network error.
+	if retryableError(&url.Error{Op: "Get", URL: "http://x", Err: &RedirectError{StatusCode: 301, Location: "http://y"}}) {
+		t.Error("expected a *url.Error wrapping a RedirectError not to be retryable")
+	}
+	if retryableError(&url.Error{Op: "Get", URL: "http://x", Err: errors.New("unsupported protocol scheme \"\"")}) {
+		t.Error("expected a *url.Error wrapping an unsupported-scheme error not to be retryable")
+	}
+	if retryableError(&url.Error{Op: "Get", URL: "http://x", Err: context.Canceled}) {
+		t.Error("expected a *url.Error wrapping context.Canceled not to be retryable")
+	}
+	if !retryableError(&url.Error{Op: "Get", URL: "http://x", Err: fakeNetErr{}}) {
+		t.Error("expected a *url.Error wrapping an actual net.Error to be retryable")
+	}
+}
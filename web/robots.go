@@ -0,0 +1,29 @@
+package web
+
+import "fmt"
+
+// RobotsMode selects how Recurse treats robots.txt. Defaults to
+// RobotsOff when empty.
+type RobotsMode string
+
+const (
+	// RobotsOff never fetches or consults robots.txt.
+	RobotsOff RobotsMode = "off"
+	// RobotsWarn fetches robots.txt and reports disallowed URLs via
+	// Progress, but still crawls them.
+	RobotsWarn RobotsMode = "warn"
+	// RobotsEnforce fetches robots.txt and drops disallowed URLs before
+	// they are enqueued, same as AllowedHosts.
+	RobotsEnforce RobotsMode = "enforce"
+)
+
+// RobotsBlockedError is reported through Progress when Config.Robots is
+// RobotsWarn or RobotsEnforce and a discovered URL is disallowed by the
+// host's robots.txt.
+type RobotsBlockedError struct {
+	URL string
+}
+
+func (e *RobotsBlockedError) Error() string {
+	return fmt.Sprintf("%s: blocked by robots.txt", e.URL)
+}
@@ -10,6 +10,12 @@ type Selectors struct {
 	Downloads []Selector
 	Prints    []Selector
 	Titles    []Selector
+
+	// CSSDownloads selects elements/attributes to scan for CSS
+	// url(...) references (e.g. inline style attributes and <style>
+	// blocks); every match is resolved against the page URL and added
+	// to Page.Downloads.
+	CSSDownloads []Selector
 }
 
 type Selector struct {
@@ -6,31 +6,99 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/frizinak/webdl/robots"
 )
 
 type Web struct {
-	c Config
+	c       Config
+	limiter *hostRateLimiter
+	robots  *robots.Cache
 }
 
 type Config struct {
 	Client      *http.Client
 	UserAgent   string
 	Concurrency int
+
+	// FollowRedirects selects how 3xx responses are handled. Defaults to
+	// RedirectFollow when empty.
+	FollowRedirects RedirectMode
+
+	// RateLimit caps requests per second to any single host. <= 0 means
+	// unlimited.
+	RateLimit float64
+
+	// Retry configures backoff retries shared by page and download
+	// fetches for transient failures (network errors, 429, 5xx).
+	Retry Retry
+
+	// OnRetry, if set, is called for every attempt that failed but will
+	// be retried, distinct from a final failure reported via Progress.
+	OnRetry func(PageInfo, *RetryError)
+
+	// Robots selects how Recurse treats each host's robots.txt (cached,
+	// see the robots package): off ignores it, warn fetches it and
+	// reports disallowed URLs via Progress without dropping them, and
+	// enforce drops them before they are enqueued. Any non-off mode
+	// honours Crawl-delay as a per-host minimum interval, whether or not
+	// RateLimit is set. Defaults to RobotsOff.
+	Robots RobotsMode
+
+	// Proxies is a list of proxy URLs (http, https or socks5) rotated
+	// round-robin across requests; a request retried after failure (see
+	// Retry) naturally advances to the next entry rather than hammering
+	// the same broken exit. Ignored when ProxySelector is set.
+	Proxies []string
+
+	// ProxySelector, if set, picks the proxy URL for every request,
+	// overriding Proxies.
+	ProxySelector ProxySelector
 }
 
 func New(c Config) *Web {
 	if c.Client == nil {
-		c.Client = http.DefaultClient
+		c.Client = &http.Client{}
 	}
 
 	if c.Concurrency <= 0 {
 		c.Concurrency = 8
 	}
 
-	return &Web{c}
+	if c.FollowRedirects == "" {
+		c.FollowRedirects = RedirectFollow
+	}
+
+	if pf := proxyFunc(c.Proxies, c.ProxySelector); pf != nil {
+		transport, ok := c.Client.Transport.(*http.Transport)
+		if !ok {
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+			c.Client.Transport = transport
+		}
+		transport.Proxy = pf
+	}
+
+	if c.FollowRedirects != RedirectFollow {
+		transport := c.Client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		c.Client.Transport = &redirectRoundTripper{next: transport, mode: c.FollowRedirects}
+		c.Client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	w := &Web{c: c, limiter: newHostRateLimiter(c.RateLimit)}
+	if c.Robots != RobotsOff && c.Robots != "" {
+		w.robots = robots.NewCache(c.Client, c.UserAgent, 0)
+	}
+
+	return w
 }
 
 type PageInfo struct {
@@ -38,6 +106,14 @@ type PageInfo struct {
 	Ref   *PageInfo
 	Title string
 	Index int
+
+	// FinalURL is the URL actually fetched after following redirects,
+	// set only when Config.FollowRedirects is RedirectRecord.
+	FinalURL *url.URL
+	// RedirectChain lists every URL requested while resolving URL to
+	// FinalURL (including URL itself), set only when
+	// Config.FollowRedirects is RedirectRecord.
+	RedirectChain []string
 }
 
 type Page struct {
@@ -50,6 +126,10 @@ type Page struct {
 type pageTask struct {
 	PageInfo
 	Download bool
+
+	// originHost is the host of the seed URL this task descends from,
+	// used to evaluate RecursiveConfig.SameHostOnly.
+	originHost string
 }
 
 func newPage(p PageInfo) *Page {
@@ -79,9 +159,28 @@ type RecursiveConfig struct {
 
 	ReverseLinks     bool
 	ReverseDownloads bool
+
+	// AllowedHosts restricts which hosts a discovered URL may belong to
+	// before it is enqueued. Entries are glob patterns matched against
+	// the URL's host, e.g. "*.example.com" or "cdn.example.com". Empty
+	// means no additional restriction beyond SameHostOnly.
+	AllowedHosts []string
+
+	// SameHostOnly, when true, only allows URLs whose host matches the
+	// host of the seed URL the crawl started from, or one of AllowedHosts.
+	SameHostOnly bool
+
+	// OnHostSkipped, if set, is called for every URL dropped because it
+	// failed the AllowedHosts / SameHostOnly check.
+	OnHostSkipped func(PageInfo)
+
+	// OnFailure, if set, is called for every URL Recurse gives up on
+	// (after any retries), in addition to it being reported through
+	// Progress and collected in Recurse's returned []Failure.
+	OnFailure func(PageInfo, error)
 }
 
-func (w *Web) Recurse(ctx context.Context, c RecursiveConfig) error {
+func (w *Web) Recurse(ctx context.Context, c RecursiveConfig) ([]Failure, error) {
 	workers := w.c.Concurrency
 	if workers <= 0 {
 		workers = 8
@@ -91,7 +190,7 @@ func (w *Web) Recurse(ctx context.Context, c RecursiveConfig) error {
 	for i, uri := range c.URLs {
 		u, err := url.Parse(uri)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		urls[i] = u
 	}
@@ -107,11 +206,74 @@ func (w *Web) Recurse(ctx context.Context, c RecursiveConfig) error {
 
 	var gerr error
 
-	doDone := func() { atomic.AddUint64(&tasks, ^uint64(0)) }
+	var failMu sync.Mutex
+	var failures []Failure
+	recordFailure := func(pi PageInfo, err error) {
+		if err == nil || errors.Is(err, context.Canceled) {
+			return
+		}
+		var rerr *RedirectError
+		if errors.As(err, &rerr) {
+			return
+		}
+
+		status := 0
+		var herr *HTTPError
+		if errors.As(err, &herr) {
+			status = herr.Status
+		}
+		referer := ""
+		if pi.Ref != nil && pi.Ref.URL != nil {
+			referer = pi.Ref.URL.String()
+		}
+
+		f := Failure{URL: pi.URL.String(), Referer: referer, Status: status, Err: err}
+		failMu.Lock()
+		failures = append(failures, f)
+		failMu.Unlock()
+
+		if c.OnFailure != nil {
+			c.OnFailure(pi, err)
+		}
+	}
+
+	hostRestricted := len(c.AllowedHosts) > 0 || c.SameHostOnly
+	allowedHost := func(host, originHost string) bool {
+		if !hostRestricted {
+			return true
+		}
+		if c.SameHostOnly && strings.EqualFold(host, originHost) {
+			return true
+		}
+
+		return MatchHosts(c.AllowedHosts, host)
+	}
+
+	// robotsAllowed reports whether u may be enqueued, and whether the
+	// caller should still report a RobotsBlockedError (true for both
+	// RobotsWarn and RobotsEnforce, the latter additionally dropping u).
+	robotsAllowed := func(u *url.URL) (allowed, blocked bool) {
+		if w.robots == nil {
+			return true, false
+		}
+		rules, err := w.robots.Get(ctx, u)
+		if err != nil {
+			return true, false
+		}
+		if d := rules.CrawlDelay(); d > 0 {
+			w.limiter.SetMinInterval(u.Host, d)
+		}
+
+		if rules.Allowed(u.Path) {
+			return true, false
+		}
+
+		return w.c.Robots != RobotsEnforce, true
+	}
 
 	var lastProgress time.Time
 	progress := func(err error, force bool) {
-		if errors.As(err, &context.Canceled) {
+		if err != nil && errors.Is(err, context.Canceled) {
 			return
 		}
 		if force || err != nil || time.Since(lastProgress) > c.ProgressInterval {
@@ -121,6 +283,89 @@ func (w *Web) Recurse(ctx context.Context, c RecursiveConfig) error {
 	}
 	defer progress(nil, true)
 
+	doDone := func() { atomic.AddUint64(&tasks, ^uint64(0)) }
+
+	// reserve accounts for n tasks about to be handed to enqueue, and
+	// must complete synchronously before any of them are fanned out on a
+	// goroutine, so tasks/dlTotal can never observe a gap between the
+	// parent task's doDone() and its children actually being counted.
+	reserve := func(n int) {
+		if n <= 0 {
+			return
+		}
+		atomic.AddUint64(&tasks, uint64(n))
+		atomic.AddUint64(&dlTotal, uint64(n))
+	}
+
+	// unreserve releases a reservation made by reserve for a pi that
+	// enqueue decided not to fan out after all.
+	unreserve := func() {
+		doDone()
+		atomic.AddUint64(&dlTotal, ^uint64(0))
+	}
+
+	// enqueue applies host scoping and robots.txt rules to pi, already
+	// reserved via reserve, before fanning it out on ch.
+	enqueue := func(pi PageInfo, download bool, originHost string) {
+		if !allowedHost(pi.URL.Host, originHost) {
+			if c.OnHostSkipped != nil {
+				c.OnHostSkipped(pi)
+			}
+			unreserve()
+			return
+		}
+		allowed, blocked := robotsAllowed(pi.URL)
+		if blocked {
+			progress(&RobotsBlockedError{URL: pi.URL.String()}, false)
+		}
+		if !allowed {
+			unreserve()
+			return
+		}
+
+		ch <- pageTask{PageInfo: pi, Download: download, originHost: originHost}
+	}
+
+	// requeueRedirect re-enqueues the Location of a RedirectError as a
+	// fresh pageTask, used when FollowRedirects is RedirectNoFollow.
+	requeueRedirect := func(u pageTask, err error) {
+		var rerr *RedirectError
+		if !errors.As(err, &rerr) {
+			return
+		}
+		loc, err := HREF(u.URL, rerr.Location)
+		if err != nil {
+			return
+		}
+
+		reserve(1)
+		go enqueue(
+			PageInfo{URL: loc, Ref: u.Ref, Title: u.Title, Index: u.Index},
+			u.Download,
+			u.originHost,
+		)
+	}
+
+	// enqueueCSSDownloads queues the assets a downloaded stylesheet
+	// itself references (e.g. fonts, background images) as further
+	// downloads, already resolved against the stylesheet's own URL.
+	enqueueCSSDownloads := func(u pageTask, urls []*url.URL) {
+		if len(urls) == 0 {
+			return
+		}
+
+		reserve(len(urls))
+		go func() {
+			for i, su := range urls {
+				enqueue(
+					PageInfo{URL: su, Ref: &u.PageInfo, Title: u.Title, Index: i},
+					true,
+					u.originHost,
+				)
+			}
+		}()
+	}
+
 	for i := 0; i < workers; i++ {
 		go func() {
 			for u := range ch {
@@ -158,53 +403,50 @@ func (w *Web) Recurse(ctx context.Context, c RecursiveConfig) error {
 						continue
 					}
 
-					err := w.download(ctx, u.PageInfo, c.DownloadCallback)
+					cssURLs, err := w.download(ctx, u.PageInfo, c.DownloadCallback)
+					requeueRedirect(u, err)
+					recordFailure(u.PageInfo, err)
 					progress(err, false)
+					enqueueCSSDownloads(u, cssURLs)
 					doDone()
 					continue
 				}
 
 				p, err := w.page(ctx, u.PageInfo, c.Selectors)
 				if err != nil {
+					requeueRedirect(u, err)
+					recordFailure(u.PageInfo, err)
 					progress(err, false)
 					doDone()
 					continue
 				}
-				atomic.AddUint64(&tasks, uint64(len(p.Links)+len(p.Downloads)))
-				atomic.AddUint64(&dlTotal, uint64(len(p.Links)+len(p.Downloads)))
-
 				if c.PrintCallback != nil && len(p.Prints) != 0 {
 					c.PrintCallback(p.PageInfo, p.Prints)
 				}
 
+				reserve(len(p.Downloads) + len(p.Links))
 				go func() {
 					for i, su := range p.Downloads {
 						ix := i
 						if c.ReverseDownloads {
 							ix = len(p.Downloads) - i - 1
 						}
-						ch <- pageTask{
-							PageInfo: PageInfo{
-								URL:   su,
-								Ref:   &p.PageInfo,
-								Title: u.Title,
-								Index: ix,
-							},
-							Download: true,
-						}
+						enqueue(
+							PageInfo{URL: su, Ref: &p.PageInfo, Title: u.Title, Index: ix},
+							true,
+							u.originHost,
+						)
 					}
 					for i, su := range p.Links {
 						ix := i
 						if c.ReverseLinks {
 							ix = len(p.Links) - i - 1
 						}
-						ch <- pageTask{
-							PageInfo: PageInfo{
-								URL:   su,
-								Ref:   &p.PageInfo,
-								Index: ix,
-							},
-						}
+						enqueue(
+							PageInfo{URL: su, Ref: &p.PageInfo, Index: ix},
+							false,
+							u.originHost,
+						)
 					}
 				}()
 
@@ -216,7 +458,7 @@ func (w *Web) Recurse(ctx context.Context, c RecursiveConfig) error {
 	}
 
 	for _, u := range urls {
-		ch <- pageTask{PageInfo: PageInfo{URL: u}}
+		ch <- pageTask{PageInfo: PageInfo{URL: u}, originHost: u.Host}
 	}
 
 	cnt := workers
@@ -225,11 +467,11 @@ func (w *Web) Recurse(ctx context.Context, c RecursiveConfig) error {
 		case <-chDone:
 			cnt--
 			if cnt == 0 {
-				return gerr
+				return failures, gerr
 			}
 		case <-time.After(time.Millisecond * 300):
 			if tasks == 0 {
-				return gerr
+				return failures, gerr
 			}
 		}
 	}